@@ -0,0 +1,42 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+// Package controllers contains the reconciliation logic for the Cluster
+// custom resource
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/pkg/specs"
+)
+
+// relabelInstancesRole patches every Pod whose role changed as a result of a
+// promotion or demotion, so that the read-write and read-only Services keep
+// selecting the right instances. It is meant to be called by the Cluster
+// reconciliation loop every time the elected primary changes.
+func relabelInstancesRole(ctx context.Context, c client.Client, pods []corev1.Pod, primaryPodName string) error {
+	originals := make(map[string]*corev1.Pod, len(pods))
+	for i := range pods {
+		originals[pods[i].Name] = pods[i].DeepCopy()
+	}
+
+	for _, pod := range specs.UpdateRoleLabels(pods, primaryPodName) {
+		original, ok := originals[pod.Name]
+		if !ok {
+			continue
+		}
+
+		if err := c.Patch(ctx, &pod, client.MergeFrom(original)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+// BackupCredentialsProvider gives the environment variables, volumes and
+// volume mounts needed by the PostgreSQL container to authenticate against
+// the object storage backend configured for backups. Every supported backend
+// (AWS, GCS, Azure) has its own implementation, selected by
+// GetBackupCredentialsProvider; S3-compatible endpoints such as MinIO are
+// served by the AWS implementations, which add the configured Endpoint and
+// Region as environment variables.
+type BackupCredentialsProvider interface {
+	// EnvVars returns the environment variables to add to the PostgreSQL container
+	EnvVars() []corev1.EnvVar
+
+	// Volumes returns the Pod-level volumes needed to expose the credentials
+	Volumes() []corev1.Volume
+
+	// VolumeMounts returns the volume mounts to add to the PostgreSQL container
+	VolumeMounts() []corev1.VolumeMount
+}
+
+// GetBackupCredentialsProvider returns the BackupCredentialsProvider matching
+// the given BackupConfiguration. When no backup is configured, it still
+// returns the static AWS provider so the (historically always emitted,
+// empty-valued when unset) AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY env vars
+// keep being set on the PostgreSQL container
+func GetBackupCredentialsProvider(backupConfiguration *v1alpha1.BackupConfiguration) BackupCredentialsProvider {
+	if backupConfiguration == nil {
+		return &awsStaticBackupCredentialsProvider{configuration: nil}
+	}
+
+	switch {
+	case backupConfiguration.AzureCredentials != nil:
+		return &azureBackupCredentialsProvider{configuration: backupConfiguration}
+	case backupConfiguration.GoogleCredentials != nil:
+		return &gcsBackupCredentialsProvider{configuration: backupConfiguration}
+	case backupConfiguration.S3Credentials.InheritFromIAMRole:
+		return &awsIRSABackupCredentialsProvider{configuration: backupConfiguration}
+	default:
+		return &awsStaticBackupCredentialsProvider{configuration: backupConfiguration}
+	}
+}
+
+// appendS3EndpointEnvVars appends the AWS_ENDPOINT_URL / AWS_REGION env vars
+// used to target a MinIO/S3-compatible endpoint instead of AWS itself
+func appendS3EndpointEnvVars(backupConfiguration *v1alpha1.BackupConfiguration, envVars []corev1.EnvVar) []corev1.EnvVar {
+	if len(backupConfiguration.Endpoint) > 0 {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "AWS_ENDPOINT_URL",
+			Value: backupConfiguration.Endpoint,
+		})
+	}
+
+	if len(backupConfiguration.Region) > 0 {
+		envVars = append(envVars, corev1.EnvVar{
+			Name:  "AWS_REGION",
+			Value: backupConfiguration.Region,
+		})
+	}
+
+	return envVars
+}
+
+// awsStaticBackupCredentialsProvider authenticates against AWS (or an
+// S3-compatible endpoint) using the static access key ID and secret access
+// key stored in a Secret, the historical way this operator supported backups
+type awsStaticBackupCredentialsProvider struct {
+	configuration *v1alpha1.BackupConfiguration
+}
+
+func (p *awsStaticBackupCredentialsProvider) EnvVars() []corev1.EnvVar {
+	envVars := []corev1.EnvVar{
+		CreateAccessKeyIDEnvVar(p.configuration),
+		CreateSecretAccessKeyEnvVar(p.configuration),
+	}
+
+	if p.configuration == nil {
+		return envVars
+	}
+
+	return appendS3EndpointEnvVars(p.configuration, envVars)
+}
+
+func (p *awsStaticBackupCredentialsProvider) Volumes() []corev1.Volume {
+	return nil
+}
+
+func (p *awsStaticBackupCredentialsProvider) VolumeMounts() []corev1.VolumeMount {
+	return nil
+}
+
+// awsIRSABackupCredentialsProvider authenticates against AWS using IAM Roles
+// for Service Accounts: a projected service-account token is exchanged for
+// temporary credentials via AWS_WEB_IDENTITY_TOKEN_FILE, with no Secret involved
+type awsIRSABackupCredentialsProvider struct {
+	configuration *v1alpha1.BackupConfiguration
+}
+
+const awsIRSATokenMountPath = "/var/run/secrets/eks.amazonaws.com/serviceaccount"
+
+func (p *awsIRSABackupCredentialsProvider) EnvVars() []corev1.EnvVar {
+	envVars := []corev1.EnvVar{
+		{
+			Name:  "AWS_WEB_IDENTITY_TOKEN_FILE",
+			Value: awsIRSATokenMountPath + "/token",
+		},
+		{
+			Name:  "AWS_ROLE_ARN",
+			Value: p.configuration.S3Credentials.RoleARN,
+		},
+	}
+
+	return appendS3EndpointEnvVars(p.configuration, envVars)
+}
+
+func (p *awsIRSABackupCredentialsProvider) Volumes() []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name: "aws-iam-token",
+			VolumeSource: corev1.VolumeSource{
+				Projected: &corev1.ProjectedVolumeSource{
+					Sources: []corev1.VolumeProjection{
+						{
+							ServiceAccountToken: &corev1.ServiceAccountTokenProjection{
+								Path:     "token",
+								Audience: "sts.amazonaws.com",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *awsIRSABackupCredentialsProvider) VolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      "aws-iam-token",
+			MountPath: awsIRSATokenMountPath,
+			ReadOnly:  true,
+		},
+	}
+}
+
+// gcsBackupCredentialsProvider authenticates against Google Cloud Storage by
+// mounting a service-account JSON key from a Secret and pointing
+// GOOGLE_APPLICATION_CREDENTIALS at it
+type gcsBackupCredentialsProvider struct {
+	configuration *v1alpha1.BackupConfiguration
+}
+
+const gcsCredentialsMountPath = "/etc/gcs-credentials"
+
+func (p *gcsBackupCredentialsProvider) EnvVars() []corev1.EnvVar {
+	return []corev1.EnvVar{
+		{
+			Name:  "GOOGLE_APPLICATION_CREDENTIALS",
+			Value: gcsCredentialsMountPath + "/key.json",
+		},
+	}
+}
+
+func (p *gcsBackupCredentialsProvider) Volumes() []corev1.Volume {
+	return []corev1.Volume{
+		{
+			Name: "gcs-credentials",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: p.configuration.GoogleCredentials.SecretName,
+					Items: []corev1.KeyToPath{
+						{
+							Key:  p.configuration.GoogleCredentials.Key,
+							Path: "key.json",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *gcsBackupCredentialsProvider) VolumeMounts() []corev1.VolumeMount {
+	return []corev1.VolumeMount{
+		{
+			Name:      "gcs-credentials",
+			MountPath: gcsCredentialsMountPath,
+			ReadOnly:  true,
+		},
+	}
+}
+
+// azureBackupCredentialsProvider authenticates against Azure Blob Storage
+// using the storage account name plus either a storage key or a SAS token,
+// both read from a Secret
+type azureBackupCredentialsProvider struct {
+	configuration *v1alpha1.BackupConfiguration
+}
+
+func (p *azureBackupCredentialsProvider) EnvVars() []corev1.EnvVar {
+	envVars := []corev1.EnvVar{
+		{
+			Name: "AZURE_STORAGE_ACCOUNT",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &p.configuration.AzureCredentials.StorageAccountReference,
+			},
+		},
+	}
+
+	if p.configuration.AzureCredentials.StorageSasTokenReference != nil {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: "AZURE_STORAGE_SAS_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: p.configuration.AzureCredentials.StorageSasTokenReference,
+			},
+		})
+	} else {
+		envVars = append(envVars, corev1.EnvVar{
+			Name: "AZURE_STORAGE_KEY",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &p.configuration.AzureCredentials.StorageKeyReference,
+			},
+		})
+	}
+
+	return envVars
+}
+
+func (p *azureBackupCredentialsProvider) Volumes() []corev1.Volume {
+	return nil
+}
+
+func (p *azureBackupCredentialsProvider) VolumeMounts() []corev1.VolumeMount {
+	return nil
+}
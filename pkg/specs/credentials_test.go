@@ -0,0 +1,114 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	"testing"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+func TestGetBackupCredentialsProviderSelection(t *testing.T) {
+	cases := []struct {
+		name          string
+		configuration *v1alpha1.BackupConfiguration
+		expectedType  BackupCredentialsProvider
+	}{
+		{
+			name:          "no backup configured falls back to the static AWS provider",
+			configuration: nil,
+			expectedType:  &awsStaticBackupCredentialsProvider{},
+		},
+		{
+			name:          "static AWS credentials",
+			configuration: &v1alpha1.BackupConfiguration{},
+			expectedType:  &awsStaticBackupCredentialsProvider{},
+		},
+		{
+			name: "AWS IRSA when InheritFromIAMRole is set",
+			configuration: &v1alpha1.BackupConfiguration{
+				S3Credentials: v1alpha1.S3Credentials{InheritFromIAMRole: true},
+			},
+			expectedType: &awsIRSABackupCredentialsProvider{},
+		},
+		{
+			name:          "GCS when GoogleCredentials is set",
+			configuration: &v1alpha1.BackupConfiguration{GoogleCredentials: &v1alpha1.GoogleCredentials{}},
+			expectedType:  &gcsBackupCredentialsProvider{},
+		},
+		{
+			name:          "Azure when AzureCredentials is set",
+			configuration: &v1alpha1.BackupConfiguration{AzureCredentials: &v1alpha1.AzureCredentials{}},
+			expectedType:  &azureBackupCredentialsProvider{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			provider := GetBackupCredentialsProvider(c.configuration)
+			if provider == nil {
+				t.Fatal("expected a non-nil provider")
+			}
+
+			gotType := typeName(provider)
+			wantType := typeName(c.expectedType)
+			if gotType != wantType {
+				t.Errorf("expected provider of type %s, got %s", wantType, gotType)
+			}
+		})
+	}
+}
+
+func TestAWSStaticProviderEnvVarsWithoutBackup(t *testing.T) {
+	provider := GetBackupCredentialsProvider(nil)
+	envVars := provider.EnvVars()
+
+	names := make(map[string]bool)
+	for _, envVar := range envVars {
+		names[envVar.Name] = true
+	}
+
+	for _, expected := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"} {
+		if !names[expected] {
+			t.Errorf("expected %s to still be set when no backup is configured", expected)
+		}
+	}
+}
+
+func TestAWSIRSAProviderMountsProjectedToken(t *testing.T) {
+	provider := GetBackupCredentialsProvider(&v1alpha1.BackupConfiguration{
+		S3Credentials: v1alpha1.S3Credentials{InheritFromIAMRole: true, RoleARN: "arn:aws:iam::123:role/backup"},
+	})
+
+	volumes := provider.Volumes()
+	if len(volumes) != 1 || volumes[0].VolumeSource.Projected == nil {
+		t.Fatalf("expected a single projected volume, got %v", volumes)
+	}
+
+	mounts := provider.VolumeMounts()
+	if len(mounts) != 1 || mounts[0].Name != volumes[0].Name {
+		t.Errorf("expected a mount matching the projected volume, got %v", mounts)
+	}
+}
+
+// typeName returns a stable string identifying the dynamic type of a
+// BackupCredentialsProvider, used to compare providers without exposing
+// their unexported fields to the test
+func typeName(provider BackupCredentialsProvider) string {
+	switch provider.(type) {
+	case *awsStaticBackupCredentialsProvider:
+		return "awsStatic"
+	case *awsIRSABackupCredentialsProvider:
+		return "awsIRSA"
+	case *gcsBackupCredentialsProvider:
+		return "gcs"
+	case *azureBackupCredentialsProvider:
+		return "azure"
+	default:
+		return "unknown"
+	}
+}
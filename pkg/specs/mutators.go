@@ -0,0 +1,124 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+// PodMutator is a function that is given a chance to alter a Pod generated for
+// a Cluster right before it is returned to the caller. Mutators are applied in
+// registration order and run after all the built-in fields have been set, so
+// they can freely append to or override the Pod's containers, volumes and
+// metadata. External integrators can use RegisterPodMutator to plug in
+// cluster-specific behavior (e.g. a different way to mount the Kubernetes API
+// access token) without forking the operator.
+type PodMutator func(cluster v1alpha1.Cluster, pod *corev1.Pod) error
+
+// podMutators is the chain of mutators that is run, in order, every time a
+// Pod is generated by this package
+var podMutators []PodMutator
+
+// RegisterPodMutator appends a PodMutator to the chain that is run whenever
+// CreatePrimaryPod, JoinReplicaInstance or PodWithExistingStorage generate a
+// Pod. It is meant to be called from an init function, either in this
+// package or in an external one importing it.
+func RegisterPodMutator(mutator PodMutator) {
+	podMutators = append(podMutators, mutator)
+}
+
+// applyPodMutators runs the registered mutator chain against the given Pod,
+// stopping at the first error
+func applyPodMutators(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+	for _, mutator := range podMutators {
+		if err := mutator(cluster, pod); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	RegisterPodMutator(extraEnvsPodMutator)
+	RegisterPodMutator(extraSidecarsPodMutator)
+	RegisterPodMutator(extraVolumesPodMutator)
+	RegisterPodMutator(extraAnnotationsPodMutator)
+}
+
+// extraEnvsPodMutator appends the environment variables listed in the
+// Cluster's ExtraEnvs to the PostgreSQL container, mirroring the extraEnvs
+// option exposed by other Postgres operators
+func extraEnvsPodMutator(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+	if len(cluster.Spec.ExtraEnvs) == 0 {
+		return nil
+	}
+
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name != PostgresContainerName {
+			continue
+		}
+
+		pod.Spec.Containers[i].Env = append(pod.Spec.Containers[i].Env, cluster.Spec.ExtraEnvs...)
+	}
+
+	return nil
+}
+
+// extraSidecarsPodMutator appends the sidecar containers listed in the
+// Cluster's ExtraContainers to the Pod
+func extraSidecarsPodMutator(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+	if len(cluster.Spec.ExtraContainers) == 0 {
+		return nil
+	}
+
+	pod.Spec.Containers = append(pod.Spec.Containers, cluster.Spec.ExtraContainers...)
+
+	return nil
+}
+
+// extraVolumesPodMutator appends the volumes and volume mounts listed in the
+// Cluster's ExtraVolumes and ExtraVolumeMounts to the Pod and the PostgreSQL
+// container respectively
+func extraVolumesPodMutator(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+	if len(cluster.Spec.ExtraVolumes) == 0 && len(cluster.Spec.ExtraVolumeMounts) == 0 {
+		return nil
+	}
+
+	pod.Spec.Volumes = append(pod.Spec.Volumes, cluster.Spec.ExtraVolumes...)
+
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name != PostgresContainerName {
+			continue
+		}
+
+		pod.Spec.Containers[i].VolumeMounts = append(
+			pod.Spec.Containers[i].VolumeMounts, cluster.Spec.ExtraVolumeMounts...)
+	}
+
+	return nil
+}
+
+// extraAnnotationsPodMutator copies the Pod-level annotations requested in the
+// Cluster's ExtraPodAnnotations into the Pod's metadata
+func extraAnnotationsPodMutator(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+	if len(cluster.Spec.ExtraPodAnnotations) == 0 {
+		return nil
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+
+	for key, value := range cluster.Spec.ExtraPodAnnotations {
+		pod.Annotations[key] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,107 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+func TestExtraEnvsPodMutator(t *testing.T) {
+	cluster := v1alpha1.Cluster{
+		Spec: v1alpha1.ClusterSpec{
+			ExtraEnvs: []corev1.EnvVar{{Name: "FOO", Value: "bar"}},
+		},
+	}
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: PostgresContainerName},
+				{Name: "sidecar"},
+			},
+		},
+	}
+
+	if err := extraEnvsPodMutator(cluster, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pod.Spec.Containers[0].Env) != 1 || pod.Spec.Containers[0].Env[0].Name != "FOO" {
+		t.Errorf("expected FOO env var on the postgres container, got %v", pod.Spec.Containers[0].Env)
+	}
+	if len(pod.Spec.Containers[1].Env) != 0 {
+		t.Errorf("expected no env vars on the sidecar container, got %v", pod.Spec.Containers[1].Env)
+	}
+}
+
+func TestExtraSidecarsPodMutator(t *testing.T) {
+	cluster := v1alpha1.Cluster{
+		Spec: v1alpha1.ClusterSpec{
+			ExtraContainers: []corev1.Container{{Name: "metrics-exporter"}},
+		},
+	}
+	pod := &corev1.Pod{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: PostgresContainerName}},
+		},
+	}
+
+	if err := extraSidecarsPodMutator(cluster, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(pod.Spec.Containers) != 2 || pod.Spec.Containers[1].Name != "metrics-exporter" {
+		t.Errorf("expected the metrics-exporter sidecar to be appended, got %v", pod.Spec.Containers)
+	}
+}
+
+func TestExtraAnnotationsPodMutator(t *testing.T) {
+	cluster := v1alpha1.Cluster{
+		Spec: v1alpha1.ClusterSpec{
+			ExtraPodAnnotations: map[string]string{"prometheus.io/scrape": "true"},
+		},
+	}
+	pod := &corev1.Pod{}
+
+	if err := extraAnnotationsPodMutator(cluster, pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pod.Annotations["prometheus.io/scrape"] != "true" {
+		t.Errorf("expected the prometheus.io/scrape annotation, got %v", pod.Annotations)
+	}
+}
+
+func TestApplyPodMutatorsStopsAtFirstError(t *testing.T) {
+	originalMutators := podMutators
+	defer func() { podMutators = originalMutators }()
+
+	expectedErr := errors.New("boom")
+	calls := 0
+	podMutators = []PodMutator{
+		func(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+			calls++
+			return expectedErr
+		},
+		func(cluster v1alpha1.Cluster, pod *corev1.Pod) error {
+			calls++
+			return nil
+		},
+	}
+
+	err := applyPodMutators(v1alpha1.Cluster{}, &corev1.Pod{})
+	if !errors.Is(err, expectedErr) {
+		t.Errorf("expected %v, got %v", expectedErr, err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the chain to stop after the first failing mutator, ran %d", calls)
+	}
+}
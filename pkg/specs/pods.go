@@ -31,6 +31,9 @@ const (
 	// ClusterRoleLabelPrimary is written in labels to represent primary servers
 	ClusterRoleLabelPrimary = "primary"
 
+	// ClusterRoleLabelReplica is written in labels to represent replica servers
+	ClusterRoleLabelReplica = "replica"
+
 	// ClusterLabelName label is applied to Pods to link them to the owning
 	// cluster
 	ClusterLabelName = "postgresql"
@@ -46,8 +49,11 @@ const (
 	postgresGroup = 26
 )
 
-// CreatePrimaryPod create a new primary instance in a Pod
-func CreatePrimaryPod(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod {
+// CreatePrimaryPod create a new primary instance in a Pod. It returns an
+// error only when a registered PodMutator rejects the generated Pod; the
+// built-in mutators never do, so callers only need to handle failures from
+// mutators they themselves register.
+func CreatePrimaryPod(cluster v1alpha1.Cluster, nodeSerial int32) (*corev1.Pod, error) {
 	podName := fmt.Sprintf("%s-%v", cluster.Name, nodeSerial)
 
 	pod := &corev1.Pod{
@@ -67,8 +73,9 @@ func CreatePrimaryPod(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod {
 			Subdomain: cluster.GetServiceAnyName(),
 			InitContainers: []corev1.Container{
 				{
-					Name:  "bootstrap-controller",
-					Image: versions.GetDefaultOperatorImageName(),
+					Name:            "bootstrap-controller",
+					Image:           versions.GetDefaultOperatorImageName(),
+					ImagePullPolicy: cluster.Spec.ImagePullPolicy,
 					Command: []string{
 						"/manager",
 						"bootstrap",
@@ -80,10 +87,12 @@ func CreatePrimaryPod(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod {
 							MountPath: "/controller",
 						},
 					},
+					Resources: cluster.Spec.InitContainerResources,
 				},
 				{
-					Name:  "bootstrap-instance",
-					Image: cluster.GetImageName(),
+					Name:            "bootstrap-instance",
+					Image:           cluster.GetImageName(),
+					ImagePullPolicy: cluster.Spec.ImagePullPolicy,
 					Env: []corev1.EnvVar{
 						{
 							Name:  "PGDATA",
@@ -136,36 +145,80 @@ func CreatePrimaryPod(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod {
 							MountPath: "/controller",
 						},
 					},
+					Resources: cluster.Spec.InitContainerResources,
 				},
 			},
-			Containers:         createPostgresContainers(cluster, podName),
-			ImagePullSecrets:   createImagePullSecrets(cluster),
-			Volumes:            createPostgresVolumes(cluster, podName),
-			Affinity:           CreateAffinitySection(cluster.Name, cluster.Spec.Affinity),
-			SecurityContext:    CreatePostgresSecurityContext(postgresUser, postgresGroup),
-			ServiceAccountName: cluster.Name,
+			Containers:                createPostgresContainers(cluster, podName),
+			ImagePullSecrets:          createImagePullSecrets(cluster),
+			Volumes:                   createPostgresVolumes(cluster, podName),
+			Affinity:                  CreateAffinitySection(cluster.Name, cluster.Spec.Affinity),
+			SecurityContext:           CreatePostgresSecurityContext(postgresUser, postgresGroup),
+			ServiceAccountName:        cluster.Name,
+			PriorityClassName:         cluster.Spec.PriorityClassName,
+			NodeSelector:              cluster.Spec.NodeSelector,
+			Tolerations:               cluster.Spec.Tolerations,
+			TopologySpreadConstraints: cluster.Spec.TopologySpreadConstraints,
 		},
 	}
 
-	return pod
+	InheritMetadata(cluster, &pod.ObjectMeta)
+
+	if err := applyPodMutators(cluster, pod); err != nil {
+		return nil, err
+	}
+
+	return pod, nil
+}
+
+// InheritMetadata merges the labels and annotations specified in the Cluster's
+// InheritedLabels and InheritedAnnotations lists into the given ObjectMeta,
+// reading their values from the Cluster object itself. Keys that are not
+// present on the Cluster are silently skipped, and keys already set on the
+// object (e.g. ClusterLabelName) are never overwritten.
+func InheritMetadata(cluster v1alpha1.Cluster, obj *metav1.ObjectMeta) {
+	for _, key := range cluster.Spec.InheritedLabels {
+		if value, ok := cluster.Labels[key]; ok {
+			if obj.Labels == nil {
+				obj.Labels = make(map[string]string)
+			}
+			if _, exists := obj.Labels[key]; !exists {
+				obj.Labels[key] = value
+			}
+		}
+	}
+
+	for _, key := range cluster.Spec.InheritedAnnotations {
+		if value, ok := cluster.Annotations[key]; ok {
+			if obj.Annotations == nil {
+				obj.Annotations = make(map[string]string)
+			}
+			if _, exists := obj.Annotations[key]; !exists {
+				obj.Annotations[key] = value
+			}
+		}
+	}
 }
 
+// createImagePullSecrets builds the list of image pull secrets to use for the
+// Pod, combining the legacy single GetImagePullSecret with the
+// Spec.ImagePullSecrets list so clusters can pull the operator and
+// PostgreSQL images from different private registries
 func createImagePullSecrets(cluster v1alpha1.Cluster) []corev1.LocalObjectReference {
 	var result []corev1.LocalObjectReference
 
-	if len(cluster.GetImagePullSecret()) == 0 {
-		return result
+	if len(cluster.GetImagePullSecret()) > 0 {
+		result = append(result, corev1.LocalObjectReference{
+			Name: cluster.GetImagePullSecret(),
+		})
 	}
 
-	result = append(result, corev1.LocalObjectReference{
-		Name: cluster.GetImagePullSecret(),
-	})
+	result = append(result, cluster.Spec.ImagePullSecrets...)
 
 	return result
 }
 
 func createPostgresVolumes(cluster v1alpha1.Cluster, podName string) []corev1.Volume {
-	return []corev1.Volume{
+	volumes := []corev1.Volume{
 		{
 			Name:         "pgdata",
 			VolumeSource: createVolumeSource(cluster, podName),
@@ -203,6 +256,10 @@ func createPostgresVolumes(cluster v1alpha1.Cluster, podName string) []corev1.Vo
 			},
 		},
 	}
+
+	volumes = append(volumes, GetBackupCredentialsProvider(cluster.Spec.Backup).Volumes()...)
+
+	return volumes
 }
 
 // createVolumeSource create the VolumeSource environment that is used
@@ -230,87 +287,103 @@ func createPostgresContainers(
 	cluster v1alpha1.Cluster,
 	podName string,
 ) []corev1.Container {
-	return []corev1.Container{
-		{
-			Name:  PostgresContainerName,
-			Image: cluster.GetImageName(),
-			Env: []corev1.EnvVar{
-				{
-					Name:  "PGDATA",
-					Value: "/var/lib/postgresql/data/pgdata",
-				},
-				{
-					Name:  "POD_NAME",
-					Value: podName,
-				},
-				{
-					Name:  "NAMESPACE",
-					Value: cluster.Namespace,
-				},
-				{
-					Name:  "CLUSTER_NAME",
-					Value: cluster.Name,
-				},
-				CreateAccessKeyIDEnvVar(cluster.Spec.Backup),
-				CreateSecretAccessKeyEnvVar(cluster.Spec.Backup),
+	container := corev1.Container{
+		Name:            PostgresContainerName,
+		Image:           cluster.GetImageName(),
+		ImagePullPolicy: cluster.Spec.ImagePullPolicy,
+		Env: []corev1.EnvVar{
+			{
+				Name:  "PGDATA",
+				Value: "/var/lib/postgresql/data/pgdata",
 			},
-			VolumeMounts: []corev1.VolumeMount{
-				{
-					Name:      "pgdata",
-					MountPath: "/var/lib/postgresql/data",
-				},
-				{
-					Name:      "controller",
-					MountPath: "/controller",
-				},
+			{
+				Name:  "POD_NAME",
+				Value: podName,
 			},
-			ReadinessProbe: &corev1.Probe{
-				TimeoutSeconds: 5,
-				Handler: corev1.Handler{
-					HTTPGet: &corev1.HTTPGetAction{
-						Path: "/readyz",
-						Port: intstr.FromInt(8000),
-					},
+			{
+				Name:  "NAMESPACE",
+				Value: cluster.Namespace,
+			},
+			{
+				Name:  "CLUSTER_NAME",
+				Value: cluster.Name,
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "pgdata",
+				MountPath: "/var/lib/postgresql/data",
+			},
+			{
+				Name:      "controller",
+				MountPath: "/controller",
+			},
+		},
+		ReadinessProbe: &corev1.Probe{
+			TimeoutSeconds: 5,
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: "/readyz",
+					Port: intstr.FromInt(8000),
 				},
 			},
-			// From K8s 1.17 and newer, startup probes will be available for
-			// all users and not just protected from feature gates. For now
-			// let's use the LivenessProbe. When we will drop support for K8s
-			// 1.16, we'll configure a StartupProbe and this will lead to a
-			// better LivenessProbe (without InitialDelaySeconds).
-			LivenessProbe: &corev1.Probe{
-				InitialDelaySeconds: cluster.GetMaxStartDelay(),
-				TimeoutSeconds:      5,
-				Handler: corev1.Handler{
-					HTTPGet: &corev1.HTTPGetAction{
-						Path: "/healthz",
-						Port: intstr.FromInt(8000),
-					},
+		},
+		// From K8s 1.17 and newer, startup probes will be available for
+		// all users and not just protected from feature gates. For now
+		// let's use the LivenessProbe. When we will drop support for K8s
+		// 1.16, we'll configure a StartupProbe and this will lead to a
+		// better LivenessProbe (without InitialDelaySeconds).
+		LivenessProbe: &corev1.Probe{
+			InitialDelaySeconds: cluster.GetMaxStartDelay(),
+			TimeoutSeconds:      5,
+			Handler: corev1.Handler{
+				HTTPGet: &corev1.HTTPGetAction{
+					Path: "/healthz",
+					Port: intstr.FromInt(8000),
 				},
 			},
-			Lifecycle: &corev1.Lifecycle{
-				PreStop: &corev1.Handler{
-					Exec: &corev1.ExecAction{
-						Command: []string{
-							"pg_ctl",
-							"stop",
-							"-m",
-							"smart",
-							"-t",
-							strconv.Itoa(int(cluster.GetMaxStopDelay())),
-						},
+		},
+		Lifecycle: &corev1.Lifecycle{
+			PreStop: &corev1.Handler{
+				Exec: &corev1.ExecAction{
+					Command: []string{
+						"pg_ctl",
+						"stop",
+						"-m",
+						"smart",
+						"-t",
+						strconv.Itoa(int(cluster.GetMaxStopDelay())),
 					},
 				},
 			},
-			Command: []string{
-				"/controller/manager",
-				"instance",
-				"run",
-				"-app-db-name", cluster.Spec.ApplicationConfiguration.Database,
-			},
-			Resources: cluster.Spec.Resources,
 		},
+		Command: []string{
+			"/controller/manager",
+			"instance",
+			"run",
+			"-app-db-name", cluster.Spec.ApplicationConfiguration.Database,
+		},
+		Resources: getPostgresResources(cluster),
 	}
+
+	provider := GetBackupCredentialsProvider(cluster.Spec.Backup)
+	container.Env = append(container.Env, provider.EnvVars()...)
+	container.VolumeMounts = append(container.VolumeMounts, provider.VolumeMounts()...)
+
+	return []corev1.Container{container}
+}
+
+// getPostgresResources returns the resource requirements to apply to the
+// PostgreSQL container, preferring the PostgresResources field but falling
+// back to the legacy Spec.Resources field so that clusters configured
+// before PostgresResources existed don't silently lose their resource
+// requests/limits
+func getPostgresResources(cluster v1alpha1.Cluster) corev1.ResourceRequirements {
+	if len(cluster.Spec.PostgresResources.Limits) > 0 || len(cluster.Spec.PostgresResources.Requests) > 0 {
+		return cluster.Spec.PostgresResources
+	}
+
+	return cluster.Spec.Resources
 }
 
 // CreateAccessKeyIDEnvVar create the environment variable giving
@@ -397,14 +470,17 @@ func CreatePostgresSecurityContext(postgresUser, postgresGroup int64) *corev1.Po
 	}
 }
 
-// JoinReplicaInstance create a new PostgreSQL node, copying the contents from another Pod
-func JoinReplicaInstance(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod {
+// JoinReplicaInstance create a new PostgreSQL node, copying the contents from
+// another Pod. It returns an error only when a registered PodMutator rejects
+// the generated Pod; the built-in mutators never do.
+func JoinReplicaInstance(cluster v1alpha1.Cluster, nodeSerial int32) (*corev1.Pod, error) {
 	podName := fmt.Sprintf("%s-%v", cluster.Name, nodeSerial)
 
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: map[string]string{
-				ClusterLabelName: cluster.Name,
+				ClusterLabelName:     cluster.Name,
+				ClusterRoleLabelName: ClusterRoleLabelReplica,
 			},
 			Annotations: map[string]string{
 				ClusterSerialAnnotationName: strconv.Itoa(int(nodeSerial)),
@@ -417,8 +493,9 @@ func JoinReplicaInstance(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod
 			Subdomain: cluster.GetServiceAnyName(),
 			InitContainers: []corev1.Container{
 				{
-					Name:  "bootstrap-controller",
-					Image: versions.GetDefaultOperatorImageName(),
+					Name:            "bootstrap-controller",
+					Image:           versions.GetDefaultOperatorImageName(),
+					ImagePullPolicy: cluster.Spec.ImagePullPolicy,
 					Command: []string{
 						"/manager",
 						"bootstrap",
@@ -430,10 +507,12 @@ func JoinReplicaInstance(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod
 							MountPath: "/controller",
 						},
 					},
+					Resources: cluster.Spec.InitContainerResources,
 				},
 				{
-					Name:  "bootstrap-replica",
-					Image: cluster.GetImageName(),
+					Name:            "bootstrap-replica",
+					Image:           cluster.GetImageName(),
+					ImagePullPolicy: cluster.Spec.ImagePullPolicy,
 					Env: []corev1.EnvVar{
 						{
 							Name:  "PGDATA",
@@ -472,22 +551,35 @@ func JoinReplicaInstance(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod
 							MountPath: "/controller",
 						},
 					},
+					Resources: cluster.Spec.InitContainerResources,
 				},
 			},
-			Containers:         createPostgresContainers(cluster, podName),
-			ImagePullSecrets:   createImagePullSecrets(cluster),
-			Volumes:            createPostgresVolumes(cluster, podName),
-			Affinity:           CreateAffinitySection(cluster.Name, cluster.Spec.Affinity),
-			SecurityContext:    CreatePostgresSecurityContext(postgresUser, postgresGroup),
-			ServiceAccountName: cluster.Name,
+			Containers:                createPostgresContainers(cluster, podName),
+			ImagePullSecrets:          createImagePullSecrets(cluster),
+			Volumes:                   createPostgresVolumes(cluster, podName),
+			Affinity:                  CreateAffinitySection(cluster.Name, cluster.Spec.Affinity),
+			SecurityContext:           CreatePostgresSecurityContext(postgresUser, postgresGroup),
+			ServiceAccountName:        cluster.Name,
+			PriorityClassName:         cluster.Spec.PriorityClassName,
+			NodeSelector:              cluster.Spec.NodeSelector,
+			Tolerations:               cluster.Spec.Tolerations,
+			TopologySpreadConstraints: cluster.Spec.TopologySpreadConstraints,
 		},
 	}
 
-	return pod
+	InheritMetadata(cluster, &pod.ObjectMeta)
+
+	if err := applyPodMutators(cluster, pod); err != nil {
+		return nil, err
+	}
+
+	return pod, nil
 }
 
-// PodWithExistingStorage create a new instance with an existing storage
-func PodWithExistingStorage(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.Pod {
+// PodWithExistingStorage create a new instance with an existing storage. It
+// returns an error only when a registered PodMutator rejects the generated
+// Pod; the built-in mutators never do.
+func PodWithExistingStorage(cluster v1alpha1.Cluster, nodeSerial int32) (*corev1.Pod, error) {
 	podName := fmt.Sprintf("%s-%v", cluster.Name, nodeSerial)
 
 	pod := &corev1.Pod{
@@ -507,8 +599,9 @@ func PodWithExistingStorage(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.
 			Subdomain: cluster.GetServiceAnyName(),
 			InitContainers: []corev1.Container{
 				{
-					Name:  "bootstrap-controller",
-					Image: versions.GetDefaultOperatorImageName(),
+					Name:            "bootstrap-controller",
+					Image:           versions.GetDefaultOperatorImageName(),
+					ImagePullPolicy: cluster.Spec.ImagePullPolicy,
 					Command: []string{
 						"/manager",
 						"bootstrap",
@@ -520,16 +613,27 @@ func PodWithExistingStorage(cluster v1alpha1.Cluster, nodeSerial int32) *corev1.
 							MountPath: "/controller",
 						},
 					},
+					Resources: cluster.Spec.InitContainerResources,
 				},
 			},
-			Containers:         createPostgresContainers(cluster, podName),
-			ImagePullSecrets:   createImagePullSecrets(cluster),
-			Volumes:            createPostgresVolumes(cluster, podName),
-			Affinity:           CreateAffinitySection(cluster.Name, cluster.Spec.Affinity),
-			SecurityContext:    CreatePostgresSecurityContext(postgresUser, postgresGroup),
-			ServiceAccountName: cluster.Name,
+			Containers:                createPostgresContainers(cluster, podName),
+			ImagePullSecrets:          createImagePullSecrets(cluster),
+			Volumes:                   createPostgresVolumes(cluster, podName),
+			Affinity:                  CreateAffinitySection(cluster.Name, cluster.Spec.Affinity),
+			SecurityContext:           CreatePostgresSecurityContext(postgresUser, postgresGroup),
+			ServiceAccountName:        cluster.Name,
+			PriorityClassName:         cluster.Spec.PriorityClassName,
+			NodeSelector:              cluster.Spec.NodeSelector,
+			Tolerations:               cluster.Spec.Tolerations,
+			TopologySpreadConstraints: cluster.Spec.TopologySpreadConstraints,
 		},
 	}
 
-	return pod
+	InheritMetadata(cluster, &pod.ObjectMeta)
+
+	if err := applyPodMutators(cluster, pod); err != nil {
+		return nil, err
+	}
+
+	return pod, nil
 }
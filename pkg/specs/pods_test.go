@@ -0,0 +1,174 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+func TestInheritMetadata(t *testing.T) {
+	cases := []struct {
+		name                string
+		cluster             v1alpha1.Cluster
+		obj                 metav1.ObjectMeta
+		expectedLabels      map[string]string
+		expectedAnnotations map[string]string
+	}{
+		{
+			name: "inherits allowlisted keys present on the Cluster",
+			cluster: v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{"owner": "team-a", "ignored": "nope"},
+					Annotations: map[string]string{"cost-center": "1234"},
+				},
+				Spec: v1alpha1.ClusterSpec{
+					InheritedLabels:      []string{"owner", "environment"},
+					InheritedAnnotations: []string{"cost-center"},
+				},
+			},
+			obj:                 metav1.ObjectMeta{},
+			expectedLabels:      map[string]string{"owner": "team-a"},
+			expectedAnnotations: map[string]string{"cost-center": "1234"},
+		},
+		{
+			name: "never overwrites a key already set on the object",
+			cluster: v1alpha1.Cluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{ClusterLabelName: "should-not-apply"},
+				},
+				Spec: v1alpha1.ClusterSpec{
+					InheritedLabels: []string{ClusterLabelName},
+				},
+			},
+			obj: metav1.ObjectMeta{
+				Labels: map[string]string{ClusterLabelName: "my-cluster"},
+			},
+			expectedLabels:      map[string]string{ClusterLabelName: "my-cluster"},
+			expectedAnnotations: nil,
+		},
+		{
+			name:                "no-op when no keys are configured",
+			cluster:             v1alpha1.Cluster{},
+			obj:                 metav1.ObjectMeta{},
+			expectedLabels:      nil,
+			expectedAnnotations: nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			InheritMetadata(c.cluster, &c.obj)
+
+			if !reflect.DeepEqual(c.obj.Labels, c.expectedLabels) {
+				t.Errorf("expected labels %v, got %v", c.expectedLabels, c.obj.Labels)
+			}
+			if !reflect.DeepEqual(c.obj.Annotations, c.expectedAnnotations) {
+				t.Errorf("expected annotations %v, got %v", c.expectedAnnotations, c.obj.Annotations)
+			}
+		})
+	}
+}
+
+func TestCreateImagePullSecrets(t *testing.T) {
+	cases := []struct {
+		name     string
+		cluster  v1alpha1.Cluster
+		expected []corev1.LocalObjectReference
+	}{
+		{
+			name:     "no secrets configured",
+			cluster:  v1alpha1.Cluster{},
+			expected: nil,
+		},
+		{
+			name: "only the legacy single secret",
+			cluster: v1alpha1.Cluster{
+				Spec: v1alpha1.ClusterSpec{
+					ImagePullSecret: "legacy-secret",
+				},
+			},
+			expected: []corev1.LocalObjectReference{{Name: "legacy-secret"}},
+		},
+		{
+			name: "legacy secret and the new list are merged, legacy first",
+			cluster: v1alpha1.Cluster{
+				Spec: v1alpha1.ClusterSpec{
+					ImagePullSecret: "legacy-secret",
+					ImagePullSecrets: []corev1.LocalObjectReference{
+						{Name: "registry-a"},
+						{Name: "registry-b"},
+					},
+				},
+			},
+			expected: []corev1.LocalObjectReference{
+				{Name: "legacy-secret"},
+				{Name: "registry-a"},
+				{Name: "registry-b"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := createImagePullSecrets(c.cluster)
+			if !reflect.DeepEqual(result, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, result)
+			}
+		})
+	}
+}
+
+func TestGetPostgresResources(t *testing.T) {
+	legacy := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")},
+	}
+	current := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+	}
+
+	cases := []struct {
+		name     string
+		cluster  v1alpha1.Cluster
+		expected corev1.ResourceRequirements
+	}{
+		{
+			name: "falls back to the legacy Resources field when PostgresResources is unset",
+			cluster: v1alpha1.Cluster{
+				Spec: v1alpha1.ClusterSpec{Resources: legacy},
+			},
+			expected: legacy,
+		},
+		{
+			name: "prefers PostgresResources when it is set",
+			cluster: v1alpha1.Cluster{
+				Spec: v1alpha1.ClusterSpec{Resources: legacy, PostgresResources: current},
+			},
+			expected: current,
+		},
+		{
+			name:     "empty when neither field is set",
+			cluster:  v1alpha1.Cluster{},
+			expected: corev1.ResourceRequirements{},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			result := getPostgresResources(c.cluster)
+			if !reflect.DeepEqual(result, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// UpdateRoleLabels walks the given Pods and makes sure the one named
+// primaryPodName carries ClusterRoleLabelPrimary while every other Pod
+// carries ClusterRoleLabelReplica, returning the subset of Pods whose role
+// label actually changed. This is what keeps the read-write and read-only
+// Services (CreateReadOnlyService and its read-write counterpart, both
+// selecting on ClusterRoleLabelName) pointed at the right instances across a
+// promotion or demotion.
+func UpdateRoleLabels(pods []corev1.Pod, primaryPodName string) []corev1.Pod {
+	var changed []corev1.Pod
+
+	for i := range pods {
+		pod := &pods[i]
+
+		role := ClusterRoleLabelReplica
+		if pod.Name == primaryPodName {
+			role = ClusterRoleLabelPrimary
+		}
+
+		if pod.Labels[ClusterRoleLabelName] == role {
+			continue
+		}
+
+		if pod.Labels == nil {
+			pod.Labels = make(map[string]string)
+		}
+		pod.Labels[ClusterRoleLabelName] = role
+
+		changed = append(changed, *pod)
+	}
+
+	return changed
+}
@@ -0,0 +1,42 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+// CreateReadOnlyService create a Service that is only directed to the
+// replica Pods of the cluster, so that applications can target hot
+// standbys directly instead of going through the read-write primary
+func CreateReadOnlyService(cluster v1alpha1.Cluster) *corev1.Service {
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cluster.GetServiceReadOnlyName(),
+			Namespace: cluster.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			Type: corev1.ServiceTypeClusterIP,
+			Selector: map[string]string{
+				ClusterLabelName:     cluster.Name,
+				ClusterRoleLabelName: ClusterRoleLabelReplica,
+			},
+			Ports: []corev1.ServicePort{
+				{
+					Port: 5432,
+				},
+			},
+		},
+	}
+
+	InheritMetadata(cluster, &service.ObjectMeta)
+
+	return service
+}
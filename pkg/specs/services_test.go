@@ -0,0 +1,40 @@
+/*
+This file is part of Cloud Native PostgreSQL.
+
+Copyright (C) 2019-2020 2ndQuadrant Italia SRL. Exclusively licensed to 2ndQuadrant Limited.
+*/
+
+package specs
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/2ndquadrant/cloud-native-postgresql/api/v1alpha1"
+)
+
+func TestCreateReadOnlyService(t *testing.T) {
+	cluster := v1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-cluster",
+			Namespace: "default",
+			Labels:    map[string]string{"owner": "team-a"},
+		},
+		Spec: v1alpha1.ClusterSpec{
+			InheritedLabels: []string{"owner"},
+		},
+	}
+
+	service := CreateReadOnlyService(cluster)
+
+	if service.Spec.Selector[ClusterLabelName] != cluster.Name {
+		t.Errorf("expected the Service to select Pods of %q, got %v", cluster.Name, service.Spec.Selector)
+	}
+	if service.Spec.Selector[ClusterRoleLabelName] != ClusterRoleLabelReplica {
+		t.Errorf("expected the Service to select only replica Pods, got %v", service.Spec.Selector)
+	}
+	if service.Labels["owner"] != "team-a" {
+		t.Errorf("expected the inherited owner label, got %v", service.Labels)
+	}
+}